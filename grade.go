@@ -0,0 +1,163 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	goexec "github.com/lfroomin/repotest/internal/exec"
+	"github.com/lfroomin/repotest/internal/workspace"
+)
+
+// runGrade implements the `repotest grade` subcommand. It locates the go.work
+// of a private tests repo, overlays the module under -problem with the
+// student's implementation at -target via a synthesized go.work, runs the
+// private tests against that overlay, and prints a pass/fail matrix. It
+// reuses internal/exec.RunAll for the actual test run, the same plumbing the
+// default workspace mode uses.
+func runGrade(args []string) error {
+	flagSet := flag.NewFlagSet("grade", flag.ExitOnError)
+	target := flagSet.String("target", "", "path to the student's implementation of -problem")
+	tests := flagSet.String("tests", "", "path to the private tests repo containing the go.work")
+	problem := flagSet.String("problem", "", "workspace subdirectory that -target replaces")
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if *target == "" || *tests == "" || *problem == "" {
+		return fmt.Errorf("grade: -target, -tests and -problem are all required")
+	}
+
+	overlayDir, overlayPaths, problemPath, err := overlayWorkspace(*tests, *problem, *target)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(overlayDir) }()
+
+	results, _, _ := goexec.RunAll(overlayDir, overlayPaths, false, goexec.DefaultCacheDir(), 1, 0, "")
+	printResults(results, true, false, 0)
+
+	if detectBenchmarks(problemPath) {
+		if err := runBenchmarkComparison(problemPath, *target); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return nil
+}
+
+// overlayWorkspace reads the go.work at testsPath, swaps the `use` entry
+// whose directory matches problem for targetPath, and writes the result to a
+// temporary go.work so the private tests run against the student's code
+// instead of the reference implementation. It returns the temp directory
+// (which acts as the new workspace root), the overlaid package paths, and
+// the original (reference) path that -problem pointed at.
+func overlayWorkspace(testsPath, problem, targetPath string) (string, []string, string, error) {
+	wPath, paths := workspace.LocateAt(testsPath)
+	if wPath == "" {
+		return "", nil, "", fmt.Errorf("grade: no go.work found under %s", testsPath)
+	}
+
+	var problemPath string
+	overlayPaths := make([]string, len(paths))
+	for i, p := range paths {
+		if filepath.Base(p) == problem {
+			problemPath = p
+			overlayPaths[i] = targetPath
+			continue
+		}
+		overlayPaths[i] = p
+	}
+	if problemPath == "" {
+		return "", nil, "", fmt.Errorf("grade: workspace at %s has no %q package", wPath, problem)
+	}
+
+	overlayDir, err := os.MkdirTemp("", "repotest-grade-")
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("go 1.21\n\nuse (\n")
+	for _, p := range overlayPaths {
+		sb.WriteString("\t" + p + "\n")
+	}
+	sb.WriteString(")\n")
+
+	if err := os.WriteFile(filepath.Join(overlayDir, "go.work"), []byte(sb.String()), 0o644); err != nil {
+		return "", nil, "", err
+	}
+
+	return overlayDir, overlayPaths, problemPath, nil
+}
+
+// detectBenchmarks reports whether any *_test.go file under dir declares a
+// Benchmark* function, in which case grade also runs a benchstat comparison.
+func detectBenchmarks(dir string) bool {
+	found := false
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err == nil && strings.Contains(string(data), "func Benchmark") {
+			found = true
+		}
+		return nil
+	})
+	return found
+}
+
+// runBenchmarkComparison benchmarks the reference implementation at
+// referenceDir and the student's implementation at targetDir, then shells out
+// to the benchstat CLI (golang.org/x/perf/cmd/benchstat) to print a
+// comparison table. If benchstat isn't on PATH, the comparison is skipped
+// with a logged message rather than failing the grade run.
+func runBenchmarkComparison(referenceDir, targetDir string) error {
+	if _, err := exec.LookPath("benchstat"); err != nil {
+		log.Println("benchstat not found on PATH, skipping benchmark comparison")
+		return nil
+	}
+
+	oldOut, err := runBenchmarks(referenceDir)
+	if err != nil {
+		return fmt.Errorf("grade: reference benchmarks: %w", err)
+	}
+	newOut, err := runBenchmarks(targetDir)
+	if err != nil {
+		return fmt.Errorf("grade: target benchmarks: %w", err)
+	}
+
+	oldFile, err := os.CreateTemp("", "repotest-bench-old-*.txt")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(oldFile.Name()) }()
+	newFile, err := os.CreateTemp("", "repotest-bench-new-*.txt")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(newFile.Name()) }()
+
+	if _, err := oldFile.Write(oldOut); err != nil {
+		return err
+	}
+	if _, err := newFile.Write(newOut); err != nil {
+		return err
+	}
+
+	out, err := exec.Command("benchstat", oldFile.Name(), newFile.Name()).CombinedOutput()
+	fmt.Println(string(out))
+	return err
+}
+
+// runBenchmarks runs `go test -bench=. -run=^$ -benchmem` for dir and returns
+// its raw output, suitable for feeding to benchstat.
+func runBenchmarks(dir string) ([]byte, error) {
+	return exec.Command("go", "test", "-bench=.", "-run=^$", "-benchmem", filepath.Join(dir, "...")).Output()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lfroomin/repotest/internal/workspace"
+)
+
+func TestOverlayWorkspace_SubstitutesProblemWithTarget(t *testing.T) {
+	testsPath := t.TempDir()
+	refDir := filepath.Join(testsPath, "problem1")
+	otherDir := filepath.Join(testsPath, "other")
+	for _, d := range []string{refDir, otherDir} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	work := "go 1.21\n\nuse (\n\t./problem1\n\t./other\n)\n"
+	if err := os.WriteFile(filepath.Join(testsPath, "go.work"), []byte(work), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	targetDir := t.TempDir()
+
+	overlayDir, overlayPaths, problemPath, err := overlayWorkspace(testsPath, "problem1", targetDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(overlayDir) }()
+
+	if problemPath != refDir {
+		t.Fatalf("problemPath = %q, want %q", problemPath, refDir)
+	}
+
+	found := false
+	for _, p := range overlayPaths {
+		if p == targetDir {
+			found = true
+		}
+		if p == refDir {
+			t.Fatalf("overlay still references the reference problem dir %q instead of the target", refDir)
+		}
+	}
+	if !found {
+		t.Fatalf("overlay paths %v do not include the target dir %q", overlayPaths, targetDir)
+	}
+
+	// The synthesized go.work must actually resolve back to the overlay
+	// paths via the same lookup the rest of repotest uses, since that's
+	// what RunAll is handed.
+	gotDir, gotPaths := workspace.LocateAt(overlayDir)
+	if gotDir != overlayDir {
+		t.Fatalf("LocateAt(overlayDir) dir = %q, want %q", gotDir, overlayDir)
+	}
+	if len(gotPaths) != len(overlayPaths) {
+		t.Fatalf("LocateAt(overlayDir) paths = %v, want %v", gotPaths, overlayPaths)
+	}
+}
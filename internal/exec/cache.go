@@ -0,0 +1,374 @@
+package exec
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"log"
+	"os"
+	goexec "os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fingerprintExts lists the non-Go file extensions that are treated as
+// config/fixture files a test might read, and so are folded into the
+// cache manifest alongside the env vars the test touches.
+var fingerprintExts = []string{".json", ".yaml", ".yml", ".env", ".toml", ".txt"}
+
+// DefaultCacheDir returns the repotest cache directory under the user's
+// home directory, falling back to a relative directory if the home
+// directory cannot be determined.
+func DefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		log.Println(err)
+		return ".repotest-cache"
+	}
+	return filepath.Join(home, ".cache", "repotest")
+}
+
+// execTestCached runs execTest through a content-aware cache keyed on the
+// package, its transitive in-workspace dependencies, the Go toolchain
+// version, and the env vars and files its tests reference. Unlike Go's own
+// test cache, the key accounts for the current value of those env vars and
+// the current content of those files, so a test that reads an env var or a
+// config file is correctly re-run when either one changes. When the test
+// files use a pattern buildManifest can't safely fingerprint (an env var or
+// file path that isn't a string literal), the package is run fresh instead
+// of being cached under an incomplete key.
+func execTestCached(ctx context.Context, wPath, dirPath, cacheDir string) ([]byte, error) {
+	manifest, safe := buildManifest(ctx, wPath, dirPath)
+	if !safe {
+		return runGoTest(ctx, wPath, dirPath, true, "")
+	}
+	key := manifest.hash()
+
+	if cached, ok := readCache(cacheDir, key); ok {
+		return cached, nil
+	}
+
+	out, err := runGoTest(ctx, wPath, dirPath, true, "")
+	if ctx.Err() == context.DeadlineExceeded {
+		// The run was killed for exceeding its -timeout, so out is empty or
+		// partial testjson. Caching it would serve that partial result to
+		// every future run that hits this key.
+		return out, err
+	}
+	writeCache(cacheDir, key, manifest, out)
+	return out, err
+}
+
+// manifestEntry is a single line of a cache manifest: "pkg DIR",
+// "toolchain VERSION", "src PATH CONTENTHASH", "dep DIR HASH",
+// "env NAME HASH", "stat PATH HASH-or-notexist", or "read PATH CONTENTHASH".
+type manifestEntry string
+
+// manifest is the package identity, toolchain version, source and dependency
+// hashes, and the env vars and files a package's tests depend on, along with
+// their current fingerprints. Its hash is used as the cache key.
+type manifest []manifestEntry
+
+// buildManifest identifies dirPath's package and hashes the content of its
+// .go files, resolves its transitive in-workspace dependencies and hashes
+// each of those too, folds in the Go toolchain version, then walks its test
+// files looking for os.Getenv / os.LookupEnv calls and string literals that
+// look like config file paths, fingerprinting each one. The package
+// identity, dependency hashes and toolchain version make sure two packages
+// never collide on the same cache key, that editing a package's own code or
+// a sibling workspace module it imports invalidates its cached result, and
+// that a toolchain upgrade does too; the env/file fingerprints are a
+// best-effort, static approximation of what a test actually touches at
+// runtime.
+//
+// The returned bool is false when a test file reads an env var or opens a
+// file through something other than a string literal (e.g. a variable or a
+// computed path) -- a pattern the static scan can't fingerprint, so the
+// returned manifest can't be trusted as a cache key for this package.
+func buildManifest(ctx context.Context, wPath, dirPath string) (manifest, bool) {
+	envNames := map[string]bool{}
+	filePaths := map[string]bool{}
+	goFiles := map[string]bool{}
+	safe := true
+
+	_ = filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if strings.HasSuffix(path, "_test.go") {
+			if !scanTestFile(path, envNames, filePaths) {
+				safe = false
+			}
+		}
+		if strings.HasSuffix(path, ".go") {
+			goFiles[path] = true
+		}
+		return nil
+	})
+
+	m := manifest{
+		manifestEntry("pkg " + dirPath),
+		manifestEntry("toolchain " + goVersion(ctx)),
+	}
+	for path := range goFiles {
+		m = append(m, manifestEntry("src "+path+" "+readHash(path)))
+	}
+	for _, dep := range workspaceDeps(ctx, wPath, dirPath) {
+		m = append(m, manifestEntry("dep "+dep+" "+hashPackageDir(dep)))
+	}
+	for name := range envNames {
+		m = append(m, manifestEntry("env "+name+" "+hashString(os.Getenv(name))))
+	}
+	for path := range filePaths {
+		m = append(m, manifestEntry("stat "+path+" "+statHash(path)))
+		m = append(m, manifestEntry("read "+path+" "+readHash(path)))
+	}
+
+	sort.Slice(m, func(i, j int) bool { return m[i] < m[j] })
+	return m, safe
+}
+
+// workspaceDeps returns the directories of dirPath's transitive dependencies
+// that live inside the workspace rooted at wPath, e.g. a sibling module
+// pulled in via a go.work "use" entry. Standard-library and third-party
+// (module-cache) dependencies are excluded: the former never changes under
+// test, and the latter is already addressed by Go's own module-version
+// pinning. dirPath's own subtree is excluded too, since it's already covered
+// by the "src" entries buildManifest adds for dirPath itself.
+func workspaceDeps(ctx context.Context, wPath, dirPath string) []string {
+	cmd := goexec.CommandContext(ctx, "go", "list", "-deps", "-f", "{{.Dir}}|{{.Standard}}", filepath.Join(dirPath, "..."))
+	cmd.Env = append(os.Environ(), "GOWORK="+filepath.Join(wPath, "go.work"))
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var deps []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		dir, standard, ok := strings.Cut(line, "|")
+		if !ok || standard == "true" || dir == "" {
+			continue
+		}
+		if dir == dirPath || strings.HasPrefix(dir, dirPath+string(filepath.Separator)) {
+			continue
+		}
+		if !strings.HasPrefix(dir, wPath) || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		deps = append(deps, dir)
+	}
+
+	sort.Strings(deps)
+	return deps
+}
+
+// hashPackageDir hashes the content of a single directory's immediate .go
+// files (not its subdirectories, since a dependency reported by `go list` is
+// always exactly one package/directory), or returns "notexist" if dir can't
+// be read.
+func hashPackageDir(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "notexist"
+	}
+
+	var files []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".go") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+
+	var sb strings.Builder
+	for _, f := range files {
+		sb.WriteString(f)
+		sb.WriteString(readHash(f))
+	}
+	return hashString(sb.String())
+}
+
+// goVersion returns `go env GOVERSION`, or "unknown" if it can't be
+// determined, so a toolchain upgrade invalidates every cached key even
+// though it doesn't change any tracked file's content.
+func goVersion(ctx context.Context) string {
+	out, err := goexec.CommandContext(ctx, "go", "env", "GOVERSION").Output()
+	if err != nil {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// scanTestFile parses a single _test.go file and records the env var names
+// passed to os.Getenv/os.LookupEnv, plus any string literal that ends in a
+// recognized fingerprintExts suffix. It returns false if the file reads an
+// env var or opens a file (os.Open/ReadFile/Stat) through an argument that
+// isn't a string literal, since such access can't be fingerprinted by this
+// static scan.
+func scanTestFile(path string, envNames, filePaths map[string]bool) bool {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, path, nil, 0)
+	if err != nil {
+		return false
+	}
+
+	safe := true
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		pkgIdent, ok := sel.X.(*ast.Ident)
+		if !ok || pkgIdent.Name != "os" {
+			return true
+		}
+
+		switch sel.Sel.Name {
+		case "Getenv", "LookupEnv":
+			if len(call.Args) == 0 {
+				return true
+			}
+			lit, ok := call.Args[0].(*ast.BasicLit)
+			if !ok || lit.Kind != token.STRING {
+				safe = false
+				return true
+			}
+			envNames[strings.Trim(lit.Value, "\"")] = true
+		case "Open", "ReadFile", "Stat":
+			if len(call.Args) == 0 {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); !ok || lit.Kind != token.STRING {
+				safe = false
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		lit, ok := n.(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		s := strings.Trim(lit.Value, "\"")
+		for _, ext := range fingerprintExts {
+			if strings.HasSuffix(s, ext) {
+				filePaths[filepath.Join(filepath.Dir(path), s)] = true
+				break
+			}
+		}
+		return true
+	})
+
+	return safe
+}
+
+// hashString returns the hex-encoded SHA256 digest of s.
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// statHash returns a hash of a file's existence/mod time, or "notexist" if
+// the file is not present.
+func statHash(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "notexist"
+	}
+	return hashString(info.ModTime().String())
+}
+
+// readHash returns a hash of a file's content, or "notexist" if the file
+// cannot be read.
+func readHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "notexist"
+	}
+	return hashString(string(data))
+}
+
+// hash computes the SHA256 of the manifest's sorted entries, used as the
+// cache key.
+func (m manifest) hash() string {
+	var sb strings.Builder
+	for _, e := range m {
+		sb.WriteString(string(e))
+		sb.WriteByte('\n')
+	}
+	return hashString(sb.String())
+}
+
+// String renders the manifest in the line-oriented format written to
+// {key}.manifest.
+func (m manifest) String() string {
+	var sb strings.Builder
+	for _, e := range m {
+		sb.WriteString(string(e))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// readCache returns the cached testjson output for key, if present.
+func readCache(cacheDir, key string) ([]byte, bool) {
+	data, err := os.ReadFile(filepath.Join(cacheDir, key+".json"))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// writeCache stores the testjson output and manifest for key, creating
+// cacheDir if necessary.
+func writeCache(cacheDir, key string, m manifest, output []byte) {
+	if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+		log.Println(err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, key+".json"), output, 0o644); err != nil {
+		log.Println(err)
+	}
+	if err := os.WriteFile(filepath.Join(cacheDir, key+".manifest"), []byte(m.String()), 0o644); err != nil {
+		log.Println(err)
+	}
+}
+
+// PruneCache removes cache entries older than maxAge from cacheDir,
+// returning the number of entries removed.
+func PruneCache(cacheDir string, maxAge time.Duration) int {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return 0
+	}
+
+	removed := 0
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".manifest") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		key := strings.TrimSuffix(e.Name(), ".manifest")
+		_ = os.Remove(filepath.Join(cacheDir, key+".manifest"))
+		_ = os.Remove(filepath.Join(cacheDir, key+".json"))
+		removed++
+	}
+	return removed
+}
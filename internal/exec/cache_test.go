@@ -0,0 +1,113 @@
+package exec
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePkg creates a minimal package directory with a non-test Go file and a
+// trivial test, so buildManifest has both a source file to hash and a
+// _test.go file to scan.
+func writePkg(t *testing.T, dir, body string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg_test.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildManifestHash_DifferentPackagesDoNotCollide(t *testing.T) {
+	root := t.TempDir()
+	dirA := filepath.Join(root, "a")
+	dirB := filepath.Join(root, "b")
+	writePkg(t, dirA, "package pkg\n")
+	writePkg(t, dirB, "package pkg\n")
+
+	keyA, _ := buildManifest(context.Background(), root, dirA)
+	keyB, _ := buildManifest(context.Background(), root, dirB)
+
+	if keyA.hash() == keyB.hash() {
+		t.Fatalf("expected distinct cache keys for distinct packages, got %q for both", keyA.hash())
+	}
+}
+
+func TestBuildManifestHash_ChangesWithSource(t *testing.T) {
+	dir := t.TempDir()
+	writePkg(t, dir, "package pkg\n\nfunc F() int { return 1 }\n")
+	beforeManifest, _ := buildManifest(context.Background(), dir, dir)
+	before := beforeManifest.hash()
+
+	writePkg(t, dir, "package pkg\n\nfunc F() int { return 2 }\n")
+	afterManifest, _ := buildManifest(context.Background(), dir, dir)
+	after := afterManifest.hash()
+
+	if before == after {
+		t.Fatal("expected cache key to change after editing the package's source")
+	}
+}
+
+func TestBuildManifestHash_StableForUnchangedPackage(t *testing.T) {
+	dir := t.TempDir()
+	writePkg(t, dir, "package pkg\n\nfunc F() int { return 1 }\n")
+
+	firstManifest, _ := buildManifest(context.Background(), dir, dir)
+	first := firstManifest.hash()
+	secondManifest, _ := buildManifest(context.Background(), dir, dir)
+	second := secondManifest.hash()
+
+	if first != second {
+		t.Fatalf("expected a stable cache key across runs, got %q then %q", first, second)
+	}
+}
+
+func TestBuildManifestSafe_FalseForIndirectEnvAccess(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pkg.go"), []byte("package pkg\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	test := `package pkg
+
+import "os"
+
+var name = "CONFIG"
+
+func init() { os.Getenv(name) }
+`
+	if err := os.WriteFile(filepath.Join(dir, "pkg_test.go"), []byte(test), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, safe := buildManifest(context.Background(), dir, dir)
+	if safe {
+		t.Fatal("expected buildManifest to report unsafe for an env var read via a variable name")
+	}
+}
+
+func TestBuildManifestSafe_TrueForLiteralEnvAccess(t *testing.T) {
+	dir := t.TempDir()
+	writePkg(t, dir, "package pkg\n")
+	test := `package pkg
+
+import "os"
+
+func init() { os.Getenv("CONFIG") }
+`
+	if err := os.WriteFile(filepath.Join(dir, "pkg_test.go"), []byte(test), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, safe := buildManifest(context.Background(), dir, dir)
+	if !safe {
+		t.Fatal("expected buildManifest to report safe when there's no indirect env/file access")
+	}
+}
@@ -0,0 +1,201 @@
+package exec
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lfroomin/repotest/internal/workspace"
+)
+
+// coverBlock is a single instrumented statement block from a go cover profile:
+// "file:startLine.startCol,endLine.endCol numStmt count".
+type coverBlock struct {
+	file                                 string
+	startLine, startCol, endLine, endCol int
+	numStmt, count                       int
+}
+
+// key identifies a block independent of its count, so blocks for the same file and
+// statement range reported by multiple packages can be merged by summing counts.
+func (b coverBlock) key() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d %d", b.file, b.startLine, b.startCol, b.endLine, b.endCol, b.numStmt)
+}
+
+// parseCoverProfile reads a go cover profile and returns its mode line and blocks.
+func parseCoverProfile(path string) (string, []coverBlock, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	var mode string
+	var blocks []coverBlock
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "mode:") {
+			mode = strings.TrimSpace(strings.TrimPrefix(line, "mode:"))
+			continue
+		}
+		b, err := parseCoverLine(line)
+		if err != nil {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", nil, err
+	}
+
+	return mode, blocks, nil
+}
+
+// parseCoverLine parses a single "file:startLine.startCol,endLine.endCol numStmt count" line.
+func parseCoverLine(line string) (coverBlock, error) {
+	fileSplit := strings.SplitN(line, ":", 2)
+	if len(fileSplit) != 2 {
+		return coverBlock{}, fmt.Errorf("malformed cover line: %s", line)
+	}
+
+	var startLine, startCol, endLine, endCol, numStmt, count int
+	_, err := fmt.Sscanf(fileSplit[1], "%d.%d,%d.%d %d %d", &startLine, &startCol, &endLine, &endCol, &numStmt, &count)
+	if err != nil {
+		return coverBlock{}, err
+	}
+
+	return coverBlock{
+		file:      fileSplit[0],
+		startLine: startLine,
+		startCol:  startCol,
+		endLine:   endLine,
+		endCol:    endCol,
+		numStmt:   numStmt,
+		count:     count,
+	}, nil
+}
+
+// coverProfileSource pairs a package's cover profile file with the absolute
+// directory it was generated from, so mergeCoverProfiles can resolve that
+// profile's import-path-qualified file fields back to workspace-relative
+// paths.
+type coverProfileSource struct {
+	path string
+	dir  string
+}
+
+// mergeCoverProfiles merges the per-package cover profiles produced by each package's
+// `go test -coverprofile` run into a single workspace-relative profile, deduping the
+// mode: header and summing counts for blocks instrumented by more than one package.
+func mergeCoverProfiles(wPath string, profiles []coverProfileSource) (map[string]coverBlock, string, error) {
+	merged := map[string]coverBlock{}
+	var order []string
+	mode := "set"
+
+	for _, src := range profiles {
+		m, blocks, err := parseCoverProfile(src.path)
+		if err != nil {
+			continue
+		}
+		if m != "" {
+			mode = m
+		}
+		modPath, modDir := workspace.ModulePath(src.dir)
+		for _, b := range blocks {
+			b.file = resolveCoverFile(wPath, modPath, modDir, b.file)
+			k := b.key()
+			if existing, ok := merged[k]; ok {
+				existing.count += b.count
+				merged[k] = existing
+				continue
+			}
+			merged[k] = b
+			order = append(order, k)
+		}
+	}
+
+	sort.Strings(order)
+
+	var sb strings.Builder
+	sb.WriteString("mode: " + mode + "\n")
+	for _, k := range order {
+		b := merged[k]
+		sb.WriteString(fmt.Sprintf("%s:%d.%d,%d.%d %d %d\n", b.file, b.startLine, b.startCol, b.endLine, b.endCol, b.numStmt, b.count))
+	}
+
+	return merged, sb.String(), nil
+}
+
+// resolveCoverFile converts a cover profile's file field -- which `go test
+// -coverprofile` writes in module-import-path form, e.g.
+// "github.com/lfroomin/repotest/internal/exec/exec.go", not as an absolute or
+// workspace-relative path -- into a path relative to wPath. modPath and
+// modDir are the module path and directory for the package the profile was
+// generated from, from workspace.ModulePath. If file doesn't match modPath,
+// or the result can't be made relative to wPath, file is returned unchanged
+// and the failure is logged so blocks don't silently collide under an empty
+// filename.
+func resolveCoverFile(wPath, modPath, modDir, file string) string {
+	if modPath == "" || !strings.HasPrefix(file, modPath+"/") {
+		log.Printf("coverage: cannot resolve module for cover file %q", file)
+		return file
+	}
+
+	abs := filepath.Join(modDir, strings.TrimPrefix(file, modPath+"/"))
+	rel, err := filepath.Rel(wPath, abs)
+	if err != nil {
+		log.Printf("coverage: cannot make %q relative to workspace %q: %v", abs, wPath, err)
+		return file
+	}
+	return rel
+}
+
+// writeCoverProfile writes the merged profile contents to path, creating parent
+// directories as necessary.
+func writeCoverProfile(path, contents string) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(path, []byte(contents), 0o644)
+}
+
+// coveragePercent returns the percentage of instrumented statements with a non-zero
+// count, i.e. the same figure `go tool cover -func` reports as "total (statements)".
+func coveragePercent(blocks []coverBlock) float64 {
+	var total, covered int
+	for _, b := range blocks {
+		total += b.numStmt
+		if b.count > 0 {
+			covered += b.numStmt
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(covered) / float64(total)
+}
+
+// workspaceCoveragePercent computes the overall coverage percentage across the
+// merged, deduped block set, used for the workspace total line.
+func workspaceCoveragePercent(merged map[string]coverBlock) float64 {
+	blocks := make([]coverBlock, 0, len(merged))
+	for _, b := range merged {
+		blocks = append(blocks, b)
+	}
+	return coveragePercent(blocks)
+}
+
+// FormatCoveragePercent renders a coverage percentage as go tool cover does, e.g. "87.5%".
+func FormatCoveragePercent(pct float64) string {
+	return strconv.FormatFloat(pct, 'f', 1, 64) + "%"
+}
@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveCoverFile_ImportPathForm(t *testing.T) {
+	wPath := t.TempDir()
+	modDir := filepath.Join(wPath, "internal", "exec")
+	if err := os.MkdirAll(modDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := resolveCoverFile(wPath, "github.com/lfroomin/repotest", wPath, "github.com/lfroomin/repotest/internal/exec/exec.go")
+	want := filepath.Join("internal", "exec", "exec.go")
+	if got != want {
+		t.Fatalf("resolveCoverFile() = %q, want %q", got, want)
+	}
+}
+
+func TestResolveCoverFile_UnresolvableFallsBackToInput(t *testing.T) {
+	wPath := t.TempDir()
+	file := "example.com/other/pkg/file.go"
+
+	got := resolveCoverFile(wPath, "", "", file)
+	if got != file {
+		t.Fatalf("resolveCoverFile() = %q, want original %q unchanged", got, file)
+	}
+}
+
+func TestMergeCoverProfiles_DistinctFilesDoNotCollide(t *testing.T) {
+	wPath := t.TempDir()
+	modDir := filepath.Join(wPath, "internal", "exec")
+	if err := os.MkdirAll(modDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(wPath, "go.mod"), []byte("module github.com/lfroomin/repotest\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := filepath.Join(t.TempDir(), "cover.out")
+	contents := "mode: set\n" +
+		"github.com/lfroomin/repotest/internal/exec/exec.go:1.1,2.2 1 1\n" +
+		"github.com/lfroomin/repotest/internal/exec/cache.go:1.1,2.2 1 0\n"
+	if err := os.WriteFile(profile, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	merged, out, err := mergeCoverProfiles(wPath, []coverProfileSource{{path: profile, dir: modDir}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 distinct blocks, got %d: %v", len(merged), merged)
+	}
+	for _, b := range merged {
+		if b.file == "" {
+			t.Fatalf("block has empty file field, merged profile is unusable:\n%s", out)
+		}
+	}
+}
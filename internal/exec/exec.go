@@ -0,0 +1,226 @@
+// Package exec runs `go test` for every package in a workspace and scans the
+// output into per-package Analysis results. It owns the content-aware test
+// cache (cache.go) and coverage aggregation (coverage.go) that sit on top of
+// that execution pipeline.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	goexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lfroomin/repotest/internal/workspace"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Analysis is a single workspace package's test results.
+type Analysis struct {
+	Label    string
+	TestExec *testjson.Execution
+	// TimeoutErr is set instead of TestExec when a package was killed for
+	// exceeding its -timeout; any testjson output it produced before that is
+	// not scanned.
+	TimeoutErr error
+	// HasCoverage, CoveragePct and CoverProfile are populated when -cover is set.
+	HasCoverage  bool
+	CoveragePct  float64
+	CoverProfile string
+	// RawOutput is the raw `go test -json` output, kept around for Reporters
+	// that need the original TestEvents rather than the testjson.Execution
+	// summary (see internal/report).
+	RawOutput []byte
+}
+
+// NewTimeoutAnalysis synthesizes a failed Analysis for a package that was
+// killed after exceeding its per-package timeout, since there is no testjson
+// output to scan.
+func NewTimeoutAnalysis(label string, timeout time.Duration) Analysis {
+	return Analysis{
+		Label:      label,
+		TimeoutErr: fmt.Errorf("test timed out after %s", timeout),
+	}
+}
+
+// ShardPaths deterministically splits paths into shards shards (via FNV hash of the
+// package label mod shards) and returns only those belonging to shard index shard.
+// Hashing the label rather than the index means a given package always lands in the
+// same shard, so re-running the same shard against a changed workspace is stable.
+func ShardPaths(wPath string, paths []string, shards, shard int) []string {
+	if shards <= 1 {
+		return paths
+	}
+
+	var subset []string
+	for _, p := range paths {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(workspace.RemoveRelativePath(wPath, p)))
+		if int(h.Sum32()%uint32(shards)) == shard {
+			subset = append(subset, p)
+		}
+	}
+	return subset
+}
+
+// RunAll runs the tests for every package, then, when coverProfileOut is set,
+// merges each package's coverage profile into a single workspace-relative
+// profile written to coverProfileOut. It returns the per-package results
+// along with the workspace-wide coverage percentage (0 when coverage was not
+// requested). Concurrency is gated through a semaphore sized p (capped at
+// runtime.NumCPU()) so large workspaces don't thrash CPU and I/O with
+// unbounded goroutines.
+func RunAll(wPath string, paths []string, useCache bool, cacheDir string, p int, timeout time.Duration, coverProfileOut string) ([]Analysis, float64, bool) {
+	if len(paths) == 0 {
+		return nil, 0, false
+	}
+
+	if p <= 0 || p > runtime.NumCPU() {
+		p = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, p)
+
+	results := make([]Analysis, 0, len(paths))
+	resultCh := make(chan Analysis, len(paths))
+
+	go func() {
+		var wg sync.WaitGroup
+		for i, p := range paths {
+			wg.Add(1)
+			go func(i int, p string) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				var coverProfile string
+				if coverProfileOut != "" {
+					coverProfile = filepath.Join(os.TempDir(), fmt.Sprintf("repotest-cover-%d-%d.out", os.Getpid(), i))
+				}
+				resultCh <- runAndAnalyze(wPath, p, useCache, cacheDir, timeout, coverProfile)
+			}(i, p)
+		}
+		wg.Wait()
+		close(resultCh)
+	}()
+
+	for r := range resultCh {
+		results = append(results, r)
+	}
+
+	if coverProfileOut == "" {
+		return results, 0, false
+	}
+
+	var profiles []coverProfileSource
+	for _, r := range results {
+		if r.CoverProfile != "" {
+			profiles = append(profiles, coverProfileSource{path: r.CoverProfile, dir: filepath.Join(wPath, r.Label)})
+		}
+	}
+	merged, contents, err := mergeCoverProfiles(wPath, profiles)
+	if err != nil {
+		log.Println(err)
+		return results, 0, false
+	}
+	if err := writeCoverProfile(coverProfileOut, contents); err != nil {
+		log.Println(err)
+	}
+	for _, src := range profiles {
+		_ = os.Remove(src.path)
+	}
+
+	return results, workspaceCoveragePercent(merged), true
+}
+
+// runAndAnalyze executes and analyzes the tests for a single package, synthesizing
+// a failed Analysis if the package does not finish within timeout. When
+// coverProfile is set, the package is run with coverage enabled and its per-package
+// coverage percentage is recorded on the returned Analysis.
+func runAndAnalyze(wPath, p string, useCache bool, cacheDir string, timeout time.Duration, coverProfile string) Analysis {
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	out, err := execTest(ctx, wPath, p, useCache, cacheDir, coverProfile)
+	if ctx.Err() == context.DeadlineExceeded {
+		return NewTimeoutAnalysis(workspace.RemoveRelativePath(wPath, p), timeout)
+	}
+	if err != nil {
+		log.Println(err)
+	}
+
+	ta := analyzeTest(wPath, p, out)
+	if coverProfile != "" {
+		if _, blocks, err := parseCoverProfile(coverProfile); err == nil {
+			ta.HasCoverage = true
+			ta.CoveragePct = coveragePercent(blocks)
+			ta.CoverProfile = coverProfile
+		}
+	}
+	return ta
+}
+
+// execTest executes all tests for a given path and the directories below. The output
+// of the tests is captured for later analysis. When useCache is enabled, the run goes
+// through the content-aware cache in cache.go instead of relying on Go's own test
+// cache, which cannot tell when a test's env vars or config files have changed.
+// Coverage runs always execute fresh, since the cache stores only the testjson
+// output and not the coverage profile.
+func execTest(ctx context.Context, wPath, dirPath string, useCache bool, cacheDir string, coverProfile string) ([]byte, error) {
+	if useCache && coverProfile == "" {
+		return execTestCached(ctx, wPath, dirPath, cacheDir)
+	}
+
+	return runGoTest(ctx, wPath, dirPath, true, coverProfile)
+}
+
+// runGoTest invokes `go test -json` for the given path and the directories below,
+// with GOWORK pinned to wPath's go.work so the run resolves packages from that
+// workspace rather than whichever one `go` would otherwise discover by walking
+// up from the process's own working directory -- which matters for the grade
+// subcommand, where wPath is a synthesized overlay workspace distinct from the
+// process's cwd. skipGoCache adds -count=1 so Go's own test cache is bypassed,
+// which repotest does whenever it is managing freshness itself via its own
+// cache. When coverProfile is set, the run additionally collects an atomic
+// coverage profile at that path.
+func runGoTest(ctx context.Context, wPath, dirPath string, skipGoCache bool, coverProfile string) ([]byte, error) {
+	testDir := filepath.Join(dirPath, "...")
+
+	args := []string{"test", "-json"}
+	if skipGoCache {
+		args = append(args, "-count=1")
+	}
+	if coverProfile != "" {
+		args = append(args, "-covermode=atomic", "-coverprofile="+coverProfile)
+	}
+	args = append(args, testDir)
+
+	cmd := goexec.CommandContext(ctx, "go", args...)
+	cmd.Env = append(os.Environ(), "GOWORK="+filepath.Join(wPath, "go.work"))
+	return cmd.Output()
+}
+
+// analyzeTest uses the testjson package to analyze the results of the test.
+func analyzeTest(wPath, path string, output []byte) Analysis {
+	testExec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: bytes.NewReader(output),
+	})
+	if err != nil {
+		log.Panic(fmt.Errorf("failed to scan testjson: %w", err))
+	}
+
+	return Analysis{
+		Label:     workspace.RemoveRelativePath(wPath, path),
+		TestExec:  testExec,
+		RawOutput: output,
+	}
+}
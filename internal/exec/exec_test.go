@@ -0,0 +1,81 @@
+package exec
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShardPaths_PartitionsWithoutOverlapOrLoss(t *testing.T) {
+	wPath := "/workspace"
+	paths := []string{
+		"/workspace/a", "/workspace/b", "/workspace/c", "/workspace/d",
+		"/workspace/e", "/workspace/f", "/workspace/g", "/workspace/h",
+	}
+	const shards = 3
+
+	seen := map[string]int{}
+	for shard := 0; shard < shards; shard++ {
+		for _, p := range ShardPaths(wPath, paths, shards, shard) {
+			if prev, ok := seen[p]; ok {
+				t.Fatalf("path %q assigned to both shard %d and shard %d", p, prev, shard)
+			}
+			seen[p] = shard
+		}
+	}
+
+	if len(seen) != len(paths) {
+		t.Fatalf("shards covered %d of %d paths: %v", len(seen), len(paths), seen)
+	}
+}
+
+func TestShardPaths_StableAcrossRuns(t *testing.T) {
+	wPath := "/workspace"
+	paths := []string{"/workspace/a", "/workspace/b", "/workspace/c", "/workspace/d"}
+
+	first := ShardPaths(wPath, paths, 3, 1)
+	second := ShardPaths(wPath, paths, 3, 1)
+
+	if len(first) != len(second) {
+		t.Fatalf("shard membership changed across runs: %v vs %v", first, second)
+	}
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("shard membership changed across runs: %v vs %v", first, second)
+		}
+	}
+}
+
+func TestShardPaths_ShardsLessThanTwoReturnsAllPaths(t *testing.T) {
+	paths := []string{"/workspace/a", "/workspace/b"}
+
+	for _, shards := range []int{0, 1} {
+		got := ShardPaths("/workspace", paths, shards, 0)
+		if len(got) != len(paths) {
+			t.Fatalf("ShardPaths with shards=%d = %v, want all paths %v", shards, got, paths)
+		}
+	}
+}
+
+func TestNewTimeoutAnalysis(t *testing.T) {
+	ta := NewTimeoutAnalysis("pkg/label", 30*time.Second)
+
+	if ta.Label != "pkg/label" {
+		t.Fatalf("Label = %q, want %q", ta.Label, "pkg/label")
+	}
+	if ta.TimeoutErr == nil {
+		t.Fatal("expected TimeoutErr to be set")
+	}
+	if ta.TestExec != nil {
+		t.Fatal("expected TestExec to be nil when TimeoutErr is set")
+	}
+}
+
+func TestRunAndAnalyze_TimeoutSynthesizesAnalysis(t *testing.T) {
+	dir := t.TempDir()
+
+	ta := runAndAnalyze(dir, dir, false, "", time.Nanosecond, "")
+
+	if ta.TimeoutErr == nil {
+		t.Fatalf("expected an expired timeout to synthesize a TimeoutErr, got %+v", ta)
+	}
+}
@@ -0,0 +1,117 @@
+package exec
+
+import (
+	"context"
+	goexec "os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/lfroomin/repotest/internal/workspace"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Event is a single TestEvent from one package's `go test -json` stream,
+// labeled with the workspace-relative package it came from.
+type Event struct {
+	Label     string
+	TestEvent testjson.TestEvent
+}
+
+// streamHandler forwards every TestEvent it sees onto a shared channel,
+// labeled with the package it belongs to, so a live consumer (e.g. the -tui
+// renderer) can update incrementally instead of waiting for the package to
+// finish.
+type streamHandler struct {
+	label  string
+	events chan<- Event
+}
+
+func (h *streamHandler) Event(event testjson.TestEvent, execution *testjson.Execution) error {
+	if h.events != nil {
+		h.events <- Event{Label: h.label, TestEvent: event}
+	}
+	return nil
+}
+
+func (h *streamHandler) Err(string) error {
+	return nil
+}
+
+// RunAllStreaming is RunAll's live-streaming counterpart: every TestEvent is
+// pushed onto events as it is scanned, instead of only becoming visible once
+// a package finishes. It does not go through the content-aware cache or
+// collect coverage, since both require a package's run to fully complete
+// before anything meaningful can be reported.
+func RunAllStreaming(wPath string, paths []string, p int, timeout time.Duration, events chan<- Event) []Analysis {
+	defer close(events)
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	if p <= 0 || p > runtime.NumCPU() {
+		p = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, p)
+
+	results := make([]Analysis, 0, len(paths))
+	resultCh := make(chan Analysis, len(paths))
+
+	var wg sync.WaitGroup
+	for _, p := range paths {
+		wg.Add(1)
+		go func(p string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			resultCh <- runStreaming(wPath, p, timeout, events)
+		}(p)
+	}
+	wg.Wait()
+	close(resultCh)
+
+	for r := range resultCh {
+		results = append(results, r)
+	}
+	return results
+}
+
+// runStreaming runs a single package's tests, scanning its `go test -json`
+// output incrementally via testjson.ScanTestOutput so events are pushed onto
+// the events channel as they happen rather than after the package exits.
+func runStreaming(wPath, path string, timeout time.Duration, events chan<- Event) Analysis {
+	label := workspace.RemoveRelativePath(wPath, path)
+
+	ctx := context.Background()
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := goexec.CommandContext(ctx, "go", "test", "-json", "-count=1", filepath.Join(path, "..."))
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return NewTimeoutAnalysis(label, timeout)
+	}
+	if err := cmd.Start(); err != nil {
+		return Analysis{Label: label}
+	}
+
+	testExec, scanErr := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout:  stdout,
+		Handler: &streamHandler{label: label, events: events},
+	})
+	_ = cmd.Wait()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return NewTimeoutAnalysis(label, timeout)
+	}
+	if scanErr != nil {
+		return Analysis{Label: label}
+	}
+
+	return Analysis{Label: label, TestExec: testExec}
+}
@@ -0,0 +1,44 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/gotestsum/testjson"
+)
+
+// buildExecution scans a hand-written `go test -json` NDJSON stream for a
+// single package with one passing, one failing, and one skipped test, so
+// Reporter tests can exercise real testjson.Execution/TestCase values
+// instead of hand-rolling them.
+func buildExecution(t *testing.T) *testjson.Execution {
+	t.Helper()
+
+	const stream = `
+{"Action":"run","Package":"pkgdir","Test":"TestPass"}
+{"Action":"output","Package":"pkgdir","Test":"TestPass","Output":"=== RUN   TestPass\n"}
+{"Action":"pass","Package":"pkgdir","Test":"TestPass","Elapsed":0.01}
+{"Action":"run","Package":"pkgdir","Test":"TestFail"}
+{"Action":"output","Package":"pkgdir","Test":"TestFail","Output":"=== RUN   TestFail\n"}
+{"Action":"output","Package":"pkgdir","Test":"TestFail","Output":"    pkg_test.go:42: boom\n"}
+{"Action":"fail","Package":"pkgdir","Test":"TestFail","Elapsed":0.02}
+{"Action":"run","Package":"pkgdir","Test":"TestSkip"}
+{"Action":"skip","Package":"pkgdir","Test":"TestSkip","Elapsed":0}
+`
+
+	return scanStream(t, stream)
+}
+
+// scanStream scans a hand-written `go test -json` NDJSON stream into a
+// *testjson.Execution.
+func scanStream(t *testing.T, stream string) *testjson.Execution {
+	t.Helper()
+
+	exec, err := testjson.ScanTestOutput(testjson.ScanConfig{
+		Stdout: strings.NewReader(strings.TrimLeft(stream, "\n")),
+	})
+	if err != nil {
+		t.Fatalf("failed to scan fixture testjson stream: %v", err)
+	}
+	return exec
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitHubReporter writes GitHub Actions `::error` workflow command
+// annotations to Writer for every failed test, so failures show up inline on
+// the PR diff. A failed test's own output is scanned for the "file.go:NN:"
+// location go test's testing package emits ahead of a t.Error/t.Fatal
+// message, so the annotation can carry a real file/line GitHub can anchor to
+// the diff; package-level failures with no such location (build errors,
+// panics, timeouts) are reported without a file= so they don't point at a
+// bogus path.
+type GitHubReporter struct {
+	Writer io.Writer
+}
+
+// failureLocRe matches the "file.go:NN:" prefix go test's testing package
+// writes ahead of a failed test's own t.Error/t.Fatal output.
+var failureLocRe = regexp.MustCompile(`^([\w./-]+\.go):(\d+):`)
+
+func (r GitHubReporter) Report(packages []Package) error {
+	for _, p := range packages {
+		if p.TimeoutErr != nil {
+			fmt.Fprintf(r.Writer, "::error::%s: %s\n", p.Label, p.TimeoutErr)
+			continue
+		}
+
+		for _, msg := range p.Exec.Errors() {
+			fmt.Fprintf(r.Writer, "::error::%s: %s\n", p.Label, msg)
+		}
+		for _, tc := range p.Exec.Failed() {
+			if file, line, ok := failureLocation(p.Exec.OutputLines(tc)); ok {
+				fmt.Fprintf(r.Writer, "::error file=%s,line=%d::test %s failed\n", filepath.Join(p.Label, file), line, tc.Test)
+				continue
+			}
+			fmt.Fprintf(r.Writer, "::error::%s: test %s failed\n", p.Label, tc.Test)
+		}
+	}
+
+	return nil
+}
+
+// failureLocation scans a failed test case's output lines for the first
+// "file.go:NN:" location and returns the file (relative to the test's own
+// package) and line number.
+func failureLocation(lines []string) (string, int, bool) {
+	for _, line := range lines {
+		m := failureLocRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		return m[1], n, true
+	}
+	return "", 0, false
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGitHubReporter_Report(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := GitHubReporter{Writer: &buf}
+
+	err := reporter.Report([]Package{{Label: "pkgdir", Exec: buildExecution(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	want := "::error file=pkgdir/pkg_test.go,line=42::test TestFail failed\n"
+	if !strings.Contains(out, want) {
+		t.Fatalf("output = %q, want it to contain %q", out, want)
+	}
+	if strings.Contains(out, "TestPass") || strings.Contains(out, "TestSkip") {
+		t.Fatalf("output = %q, expected only the failing test to be annotated", out)
+	}
+}
+
+func TestGitHubReporter_Report_TimeoutHasNoFile(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := GitHubReporter{Writer: &buf}
+
+	err := reporter.Report([]Package{{Label: "pkgdir", TimeoutErr: os.ErrDeadlineExceeded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "file=") {
+		t.Fatalf("output = %q, a timeout has no source location and should not emit file=", out)
+	}
+	if !strings.Contains(out, "::error::pkgdir:") {
+		t.Fatalf("output = %q, want a package-level ::error:: annotation", out)
+	}
+}
+
+func TestGitHubReporter_Report_FailureWithoutLocationHasNoFile(t *testing.T) {
+	const stream = `{"Action":"run","Package":"pkgdir","Test":"TestFail"}
+{"Action":"output","Package":"pkgdir","Test":"TestFail","Output":"panic: boom\n"}
+{"Action":"fail","Package":"pkgdir","Test":"TestFail","Elapsed":0.01}
+`
+	exec := scanStream(t, stream)
+
+	var buf bytes.Buffer
+	reporter := GitHubReporter{Writer: &buf}
+
+	if err := reporter.Report([]Package{{Label: "pkgdir", Exec: exec}}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "file=") {
+		t.Fatalf("output = %q, a failure with no parseable location should not emit file=", out)
+	}
+	if !strings.Contains(out, "test TestFail failed") {
+		t.Fatalf("output = %q, want the failing test named in the annotation", out)
+	}
+}
@@ -0,0 +1,48 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+)
+
+// JSONReporter streams a single combined NDJSON file of every package's raw
+// `go test -json` TestEvents, with each line's object extended with a
+// "label" field identifying its workspace package, so downstream tooling
+// like gotestsum can re-parse the combined stream.
+type JSONReporter struct {
+	Path string
+}
+
+func (r JSONReporter) Report(packages []Package) error {
+	f, err := os.Create(r.Path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	w := bufio.NewWriter(f)
+	defer func() { _ = w.Flush() }()
+
+	for _, p := range packages {
+		scanner := bufio.NewScanner(bytes.NewReader(p.RawOutput))
+		for scanner.Scan() {
+			var event map[string]interface{}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			event["label"] = p.Label
+
+			line, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			if _, err := w.Write(append(line, '\n')); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
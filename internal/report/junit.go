@@ -0,0 +1,89 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+)
+
+// JUnitReporter writes a JUnit-schema XML report to Path, one <testsuite> per
+// workspace package and one <testcase> per test, suitable for Jenkins/GitLab/
+// CircleCI test reporters.
+type JUnitReporter struct {
+	Path string
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     float64         `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name    string        `xml:"name,attr"`
+	Time    float64       `xml:"time,attr"`
+	Failure *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+func (r JUnitReporter) Report(packages []Package) error {
+	suites := junitTestSuites{}
+
+	for _, p := range packages {
+		if p.TimeoutErr != nil {
+			suites.Suites = append(suites.Suites, junitTestSuite{
+				Name:     p.Label,
+				Tests:    1,
+				Failures: 1,
+				Cases: []junitTestCase{{
+					Name:    p.Label,
+					Failure: &junitFailure{Message: p.TimeoutErr.Error()},
+				}},
+			})
+			continue
+		}
+
+		suite := junitTestSuite{
+			Name:   p.Label,
+			Tests:  p.Exec.Total(),
+			Errors: len(p.Exec.Errors()),
+			Time:   p.Exec.Elapsed().Seconds(),
+		}
+
+		for _, name := range p.Exec.Packages() {
+			for _, tc := range p.Exec.Package(name).Passed {
+				suite.Cases = append(suite.Cases, junitTestCase{Name: string(tc.Test), Time: tc.Elapsed.Seconds()})
+			}
+		}
+		for _, tc := range p.Exec.Skipped() {
+			suite.Cases = append(suite.Cases, junitTestCase{Name: string(tc.Test), Time: tc.Elapsed.Seconds()})
+		}
+		for _, tc := range p.Exec.Failed() {
+			suite.Failures++
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:    string(tc.Test),
+				Time:    tc.Elapsed.Seconds(),
+				Failure: &junitFailure{Message: "test failed"},
+			})
+		}
+
+		suites.Suites = append(suites.Suites, suite)
+	}
+
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(r.Path, append([]byte(xml.Header), data...), 0o644)
+}
@@ -0,0 +1,91 @@
+package report
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestJUnitReporter_Report(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	reporter := JUnitReporter{Path: path}
+
+	err := reporter.Report([]Package{{Label: "pkgdir", Exec: buildExecution(t)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("output is not valid JUnit XML: %v\n%s", err, data)
+	}
+
+	if len(suites.Suites) != 1 {
+		t.Fatalf("expected 1 testsuite, got %d", len(suites.Suites))
+	}
+	suite := suites.Suites[0]
+
+	if suite.Name != "pkgdir" {
+		t.Errorf("suite.Name = %q, want %q", suite.Name, "pkgdir")
+	}
+	if suite.Tests != 3 {
+		t.Errorf("suite.Tests = %d, want 3", suite.Tests)
+	}
+	if suite.Failures != 1 {
+		t.Errorf("suite.Failures = %d, want 1", suite.Failures)
+	}
+	if len(suite.Cases) != suite.Tests {
+		t.Errorf("len(suite.Cases) = %d, want suite.Tests = %d", len(suite.Cases), suite.Tests)
+	}
+
+	var passCase, failCase, skipCase *junitTestCase
+	for i, c := range suite.Cases {
+		switch c.Name {
+		case "TestPass":
+			passCase = &suite.Cases[i]
+		case "TestFail":
+			failCase = &suite.Cases[i]
+		case "TestSkip":
+			skipCase = &suite.Cases[i]
+		}
+	}
+	if passCase == nil || passCase.Failure != nil {
+		t.Errorf("TestPass case = %+v, want a case with no Failure", passCase)
+	}
+	if failCase == nil || failCase.Failure == nil {
+		t.Errorf("TestFail case = %+v, want a case with a Failure", failCase)
+	}
+	if skipCase == nil || skipCase.Failure != nil {
+		t.Errorf("TestSkip case = %+v, want a case with no Failure", skipCase)
+	}
+}
+
+func TestJUnitReporter_Report_Timeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "junit.xml")
+	reporter := JUnitReporter{Path: path}
+
+	err := reporter.Report([]Package{{Label: "pkgdir", TimeoutErr: os.ErrDeadlineExceeded}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("output is not valid JUnit XML: %v\n%s", err, data)
+	}
+
+	if len(suites.Suites) != 1 || suites.Suites[0].Failures != 1 {
+		t.Fatalf("expected a single failing suite for a timed-out package, got %+v", suites.Suites)
+	}
+}
@@ -0,0 +1,28 @@
+// Package report renders a workspace test run to a CI-consumable format.
+// reporterFor in package main selects a Reporter based on the -format flag so new formats (e.g.
+// TeamCity, TAP) can be added without touching the execution pipeline.
+package report
+
+import (
+	"gotest.tools/gotestsum/testjson"
+)
+
+// Package is one workspace package's test results, as handed to a Reporter.
+type Package struct {
+	Label string
+	// Exec is nil when TimeoutErr is set, since there is no testjson output
+	// to report on in that case.
+	Exec *testjson.Execution
+	// TimeoutErr is set instead of Exec when the package was killed for
+	// exceeding its -timeout.
+	TimeoutErr error
+	// RawOutput is the raw `go test -json` NDJSON output for the package,
+	// used by Reporters (the json Reporter) that need the original TestEvents.
+	RawOutput []byte
+}
+
+// Reporter writes a report for a full workspace run to its destination -- a
+// file, stdout, or both, depending on the format.
+type Reporter interface {
+	Report(packages []Package) error
+}
@@ -0,0 +1,133 @@
+// Package workspace locates a Go workspace's go.work file and the packages
+// listed within it.
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const workFile = "go.work"
+
+// Locate finds the go.work file by starting at the current directory and
+// traversing towards the root directory until a workspace file is found. It
+// returns the directory containing the workspace file and the list of
+// packages declared within it.
+func Locate() (string, []string) {
+	path, err := os.Getwd()
+	if err != nil {
+		log.Println(err)
+		return "", nil
+	}
+	return LocateAt(path)
+}
+
+// LocateAt is Locate, but rooted at an arbitrary starting directory instead
+// of the current working directory, so callers (e.g. the grade subcommand)
+// can locate a go.work inside an arbitrary repo.
+func LocateAt(root string) (string, []string) {
+	path := root
+	for {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			log.Println(err)
+			return "", nil
+		}
+
+		for _, e := range entries {
+			if e.Name() == workFile {
+				return path, readWorkspaceFile(path, e.Name())
+			}
+		}
+
+		// The workspace file was not found in this directory, so set the
+		// path to the parent directory for the next loop iteration.
+		parent := filepath.Dir(path)
+
+		// Check if the new path is the root directory
+		if len(parent) == 1 || parent == path {
+			return "", nil
+		}
+		path = parent
+	}
+}
+
+// readWorkspaceFile reads the go.work file and returns the list of packages contained
+// within the "use( ... )" syntax. Relative package locations found in the workspace file
+// are concatenated with the input path to create file names that include a full path;
+// absolute locations are returned as they are.
+func readWorkspaceFile(path, filename string) []string {
+	fullFilename := filepath.Join(path, filename)
+	f, err := os.Open(fullFilename)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	defer func(f *os.File) {
+		err := f.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(f)
+
+	var packages []string
+	scanner := bufio.NewScanner(f)
+	beginCapture := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		if beginCapture && !strings.Contains(line, ")") {
+			dirName := strings.TrimSpace(line)
+			if !filepath.IsAbs(dirName) {
+				dirName = filepath.Join(path, dirName)
+			}
+			packages = append(packages, dirName)
+		} else if strings.HasPrefix(line, "use (") {
+			beginCapture = true
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	return packages
+}
+
+// ModulePath walks from dir towards the root looking for a go.mod, and
+// returns the module path declared in its "module " line along with the
+// directory containing it. It returns two empty strings if no go.mod is
+// found, e.g. when dir is outside any module.
+func ModulePath(dir string) (modPath, modDir string) {
+	path := dir
+	for {
+		data, err := os.ReadFile(filepath.Join(path, "go.mod"))
+		if err == nil {
+			scanner := bufio.NewScanner(bytes.NewReader(data))
+			for scanner.Scan() {
+				line := strings.TrimSpace(scanner.Text())
+				if strings.HasPrefix(line, "module ") {
+					return strings.TrimSpace(strings.TrimPrefix(line, "module ")), path
+				}
+			}
+			return "", path
+		}
+
+		parent := filepath.Dir(path)
+		if len(parent) == 1 || parent == path {
+			return "", ""
+		}
+		path = parent
+	}
+}
+
+// RemoveRelativePath strips the workspace path prefix from the supplied path.
+// This provides a shorter, less repetitive label for the test results.
+func RemoveRelativePath(wPath, path string) string {
+	shortPath, _ := filepath.Rel(wPath, path)
+	return shortPath
+}
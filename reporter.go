@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	goexec "github.com/lfroomin/repotest/internal/exec"
+	"github.com/lfroomin/repotest/internal/report"
+)
+
+// reportResults renders results in the named CI format (anything other than
+// "pretty") via the internal/report package and writes it to its default
+// destination: a file under the workspace root, or stdout for "github".
+func reportResults(format, wPath string, results []goexec.Analysis) error {
+	reporter, dest, err := reporterFor(format, wPath)
+	if err != nil {
+		return err
+	}
+
+	if err := reporter.Report(toReportPackages(results)); err != nil {
+		return err
+	}
+
+	if dest != "" {
+		fmt.Printf("Wrote %s report to %s\n", format, dest)
+	}
+	return nil
+}
+
+// reporterFor builds the report.Reporter for the named format, along with the
+// path it was written to (empty for formats that write to stdout).
+func reporterFor(format, wPath string) (report.Reporter, string, error) {
+	switch format {
+	case "junit":
+		dest := filepath.Join(wPath, "junit.xml")
+		return report.JUnitReporter{Path: dest}, dest, nil
+	case "json":
+		dest := filepath.Join(wPath, "results.ndjson")
+		return report.JSONReporter{Path: dest}, dest, nil
+	case "github":
+		return report.GitHubReporter{Writer: os.Stdout}, "", nil
+	default:
+		return nil, "", fmt.Errorf("unknown -format %q: want pretty, junit, json, or github", format)
+	}
+}
+
+// toReportPackages converts the internal/exec results into the report.Package
+// shape the internal/report Reporters operate on.
+func toReportPackages(results []goexec.Analysis) []report.Package {
+	packages := make([]report.Package, 0, len(results))
+	for _, ta := range results {
+		packages = append(packages, report.Package{
+			Label:      ta.Label,
+			Exec:       ta.TestExec,
+			TimeoutErr: ta.TimeoutErr,
+			RawOutput:  ta.RawOutput,
+		})
+	}
+	return packages
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	goexec "github.com/lfroomin/repotest/internal/exec"
+	"gotest.tools/gotestsum/testjson"
+)
+
+// runTUI runs the workspace in streaming mode, rendering a live-updating
+// table of per-package progress as TestEvents arrive instead of waiting for
+// every package to finish before printing anything. When stdout is not a
+// terminal (e.g. piped to a CI log), it falls back to plain line-buffered
+// streaming instead of redrawing a table with ANSI cursor moves.
+func runTUI(wPath string, paths []string, p int, timeout time.Duration) {
+	events := make(chan goexec.Event, 256)
+	resultsCh := make(chan []goexec.Analysis, 1)
+
+	go func() {
+		resultsCh <- goexec.RunAllStreaming(wPath, paths, p, timeout, events)
+	}()
+
+	if stdoutIsTTY() {
+		renderLiveTable(events)
+	} else {
+		renderLineStream(events)
+	}
+
+	printResults(<-resultsCh, true, false, 0)
+}
+
+// stdoutIsTTY reports whether stdout is attached to a terminal.
+func stdoutIsTTY() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// pkgProgress tracks one package's running/passed/failed/skipped counts for
+// the live table.
+type pkgProgress struct {
+	running, passed, failed, skipped int
+	start                            time.Time
+}
+
+// renderLiveTable consumes events and redraws a table of per-package
+// progress in place using ANSI cursor moves, throttled to avoid flickering.
+func renderLiveTable(events <-chan goexec.Event) {
+	progress := map[string]*pkgProgress{}
+	var order []string
+	linesPrinted := 0
+
+	ticker := time.NewTicker(150 * time.Millisecond)
+	defer ticker.Stop()
+
+	dirty := false
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				drawTable(progress, order, &linesPrinted)
+				return
+			}
+			p, exists := progress[ev.Label]
+			if !exists {
+				p = &pkgProgress{start: time.Now()}
+				progress[ev.Label] = p
+				order = append(order, ev.Label)
+			}
+			applyEvent(p, ev)
+			dirty = true
+		case <-ticker.C:
+			if dirty {
+				drawTable(progress, order, &linesPrinted)
+				dirty = false
+			}
+		}
+	}
+}
+
+// applyEvent updates a package's running/passed/failed/skipped counts from a
+// single per-test TestEvent. Package-level events (Test == "") are ignored;
+// the table only tracks individual test outcomes.
+func applyEvent(p *pkgProgress, ev goexec.Event) {
+	if ev.TestEvent.Test == "" {
+		return
+	}
+	switch ev.TestEvent.Action {
+	case testjson.ActionRun:
+		p.running++
+	case testjson.ActionPass:
+		p.running--
+		p.passed++
+	case testjson.ActionFail:
+		p.running--
+		p.failed++
+	case testjson.ActionSkip:
+		p.running--
+		p.skipped++
+	}
+}
+
+// drawTable redraws the progress table in place: it moves the cursor back up
+// over the previous draw, then reprints every row.
+func drawTable(progress map[string]*pkgProgress, order []string, linesPrinted *int) {
+	if *linesPrinted > 0 {
+		fmt.Printf("\u001b[%dA", *linesPrinted)
+	}
+	for _, label := range order {
+		p := progress[label]
+		fmt.Printf("\u001b[2K%-40s running=%-3d passed=%-3d failed=%-3d skipped=%-3d elapsed=%s\n",
+			label, p.running, p.passed, p.failed, p.skipped, testjson.FormatDurationAsSeconds(time.Since(p.start), 1))
+	}
+	*linesPrinted = len(order)
+}
+
+// renderLineStream prints one line per test event as it arrives, for
+// non-TTY stdout where cursor-addressed redraws wouldn't render correctly.
+func renderLineStream(events <-chan goexec.Event) {
+	for ev := range events {
+		if ev.TestEvent.Test == "" {
+			continue
+		}
+		fmt.Printf("%s %s %s\n", ev.Label, ev.TestEvent.Action, ev.TestEvent.Test)
+	}
+}